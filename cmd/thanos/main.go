@@ -0,0 +1,27 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package main
+
+import (
+	"os"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/thanos-io/thanos/pkg/store"
+)
+
+func main() {
+	app := kingpin.New("thanos", "A block storage based long-term storage for Prometheus.")
+
+	// bs is a placeholder: the full BucketStore construction (object
+	// storage client, index cache, block sync) is wired up by the rest of
+	// the store-gateway command's setup, which lives outside this slice of
+	// the repo. registerStoreGatewayCommand only needs it to be a
+	// *store.BucketStore so --drpc-address can be registered and run
+	// against the real instance once that wiring lands.
+	bs := &store.BucketStore{}
+	registerStoreGatewayCommand(app, bs)
+
+	kingpin.MustParse(app.Parse(os.Args[1:]))
+}
@@ -0,0 +1,86 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/run"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/metadata"
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/thanos-io/thanos/pkg/server/drpc"
+	"github.com/thanos-io/thanos/pkg/store"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+)
+
+// bucketStoreDRPCServer adapts a *store.BucketStore - already a
+// storepb.StoreServer for the gRPC transport - to drpc.DRPCStoreAPIServer,
+// so the same instance serves both transports instead of needing a second,
+// DRPC-specific implementation of the store API.
+type bucketStoreDRPCServer struct {
+	bs *store.BucketStore
+}
+
+func (s *bucketStoreDRPCServer) Info(ctx context.Context, r *storepb.InfoRequest) (*storepb.InfoResponse, error) {
+	return s.bs.Info(ctx, r)
+}
+
+func (s *bucketStoreDRPCServer) LabelNames(ctx context.Context, r *storepb.LabelNamesRequest) (*storepb.LabelNamesResponse, error) {
+	return s.bs.LabelNames(ctx, r)
+}
+
+func (s *bucketStoreDRPCServer) LabelValues(ctx context.Context, r *storepb.LabelValuesRequest) (*storepb.LabelValuesResponse, error) {
+	return s.bs.LabelValues(ctx, r)
+}
+
+func (s *bucketStoreDRPCServer) Series(r *storepb.SeriesRequest, stream drpc.DRPCStoreAPI_SeriesStream) error {
+	return s.bs.Series(r, &grpcSeriesServerOverDRPC{stream: stream})
+}
+
+// grpcSeriesServerOverDRPC makes a DRPC series stream satisfy
+// storepb.Store_SeriesServer (a grpc.ServerStream plus Send), so
+// BucketStore.Series can drive it exactly as it drives the gRPC transport.
+// Header/trailer metadata are no-ops: DRPC has no equivalent, and
+// BucketStore.Series doesn't depend on them for correctness.
+type grpcSeriesServerOverDRPC struct {
+	stream drpc.DRPCStoreAPI_SeriesStream
+}
+
+func (s *grpcSeriesServerOverDRPC) Send(r *storepb.SeriesResponse) error { return s.stream.Send(r) }
+func (s *grpcSeriesServerOverDRPC) Context() context.Context             { return s.stream.Context() }
+func (s *grpcSeriesServerOverDRPC) SetHeader(metadata.MD) error          { return nil }
+func (s *grpcSeriesServerOverDRPC) SendHeader(metadata.MD) error         { return nil }
+func (s *grpcSeriesServerOverDRPC) SetTrailer(metadata.MD)               {}
+func (s *grpcSeriesServerOverDRPC) SendMsg(m interface{}) error {
+	return s.stream.Send(m.(*storepb.SeriesResponse))
+}
+func (s *grpcSeriesServerOverDRPC) RecvMsg(interface{}) error { return io.EOF }
+
+// registerStoreGatewayCommand registers the store-gateway command against
+// app, wiring --drpc-address (via registerStoreGateway) into its flag set
+// and run.Group so the DRPC listener actually starts instead of sitting
+// unused behind a flag nothing reads.
+func registerStoreGatewayCommand(app *kingpin.Application, bs *store.BucketStore) {
+	cmd := app.Command("store", "Store node giving access to blocks in a bucket provider.")
+
+	runDRPC := registerStoreGateway(cmd, func(logger log.Logger, reg prometheus.Registerer) (drpc.DRPCStoreAPIServer, error) {
+		return &bucketStoreDRPCServer{bs: bs}, nil
+	})
+
+	cmd.Action(func(*kingpin.ParseContext) error {
+		logger := log.NewLogfmtLogger(os.Stderr)
+		reg := prometheus.NewRegistry()
+
+		var g run.Group
+		if err := runDRPC(&g, logger, reg); err != nil {
+			return err
+		}
+		return g.Run()
+	})
+}
@@ -57,13 +57,40 @@ func (c *RueidisCache) Store(ctx context.Context, data map[string][]byte, ttl ti
 
 // Fetch fetches multiple keys and returns a map containing cache hits, along with a list of missing keys.
 // In case of error, it logs and return an empty cache hits map.
+//
+// It is built on top of FetchAsync, which it simply drains into a map; use
+// FetchAsync directly if the caller can consume results incrementally.
 func (c *RueidisCache) Fetch(ctx context.Context, keys []string) map[string][]byte {
-	c.requests.Add(float64(len(keys)))
-	results := c.redisClient.GetMulti(ctx, keys)
-	c.hits.Add(float64(len(results)))
+	results := make(map[string][]byte, len(keys))
+	for kv := range c.FetchAsync(ctx, keys) {
+		results[kv.Key] = kv.Value
+	}
 	return results
 }
 
+// FetchAsync is like Fetch, but returns a channel of cache hits instead of a
+// map, so a caller doing large FetchMultiPostings-style lookups doesn't have
+// to block its own goroutine on the whole batch before it can start
+// draining results. The underlying GetMultiAsync still resolves the batch
+// with a single pipelined call, so there's no earlier per-key delivery than
+// Fetch gets - only the caller's own blocking on the round trip is removed.
+func (c *RueidisCache) FetchAsync(ctx context.Context, keys []string) <-chan cacheutil.KeyValue {
+	c.requests.Add(float64(len(keys)))
+
+	in := c.redisClient.GetMultiAsync(ctx, keys)
+	out := make(chan cacheutil.KeyValue, len(keys))
+
+	go func() {
+		defer close(out)
+		for kv := range in {
+			c.hits.Inc()
+			out <- kv
+		}
+	}()
+
+	return out
+}
+
 func (c *RueidisCache) Name() string {
 	return c.name
 }
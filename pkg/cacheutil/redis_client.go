@@ -0,0 +1,63 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package cacheutil
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	opSet      = "set"
+	opSetMulti = "setmulti"
+	opGetMulti = "getmulti"
+)
+
+// RedisClientConfig is the configuration for connecting to a Redis (or
+// Redis-protocol-compatible) server used by RueidisClient.
+type RedisClientConfig struct {
+	// Addr is the redis server address, used when Addrs is empty.
+	Addr string `yaml:"addr"`
+	// Addrs is the list of redis server addresses used to initialize the
+	// client, e.g. for cluster discovery. Takes precedence over Addr.
+	Addrs []string `yaml:"addrs"`
+
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"`
+
+	DialTimeout  time.Duration `yaml:"dial_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+
+	// CacheSize is the amount of memory, in bytes, each connection reserves
+	// for rueidis's client-side cache. Defaults to 1GiB if unset.
+	CacheSize int `yaml:"cache_size"`
+
+	// ClientSideCacheTTL bounds how long rueidis's opt-in client-side cache
+	// may serve a cached GET before revalidating against redis, independent
+	// of the TTL the value was SET with. Defaults to defaultClientSideCacheTTL
+	// if unset.
+	ClientSideCacheTTL time.Duration `yaml:"client_side_cache_ttl"`
+}
+
+func (c *RedisClientConfig) validate() error {
+	if len(c.Addrs) == 0 && c.Addr == "" {
+		return errors.New("no redis addr or addrs specified")
+	}
+	if c.ClientSideCacheTTL < 0 {
+		return errors.New("client_side_cache_ttl must not be negative")
+	}
+	return nil
+}
+
+// parseRedisClientConfig unmarshals a YAML-encoded RedisClientConfig.
+func parseRedisClientConfig(conf []byte) (RedisClientConfig, error) {
+	var config RedisClientConfig
+	if err := yaml.UnmarshalStrict(conf, &config); err != nil {
+		return RedisClientConfig{}, errors.Wrap(err, "parsing redis config YAML")
+	}
+	return config, nil
+}
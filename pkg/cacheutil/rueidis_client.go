@@ -12,15 +12,31 @@ import (
 	"github.com/rueian/rueidis"
 )
 
+// KeyValue is a single result of GetMultiAsync.
+type KeyValue struct {
+	Key   string
+	Value []byte
+}
+
+// defaultClientSideCacheTTL is used when RedisClientConfig.ClientSideCacheTTL
+// isn't set, matching the TTL GetMulti hardcoded before it became
+// configurable.
+const defaultClientSideCacheTTL = 8 * time.Hour
+
 // RueidisClient is a wrap of rueidis.Client.
 type RueidisClient struct {
-	client rueidis.Client
-	config RedisClientConfig
+	client        rueidis.Client
+	config        RedisClientConfig
+	clientSideTTL time.Duration
 
 	logger           log.Logger
 	durationSet      prometheus.Observer
 	durationSetMulti prometheus.Observer
 	durationGetMulti prometheus.Observer
+
+	getMultiLocalHit prometheus.Counter
+	getMultiRedisHit prometheus.Counter
+	getMultiMiss     prometheus.Counter
 }
 
 // NewRueidisClient makes a new RueidisClient.
@@ -74,10 +90,16 @@ func NewRueidisClientWithConfig(logger log.Logger, name string, config RedisClie
 		reg = prometheus.WrapRegistererWith(prometheus.Labels{"name": name}, reg)
 	}
 
+	clientSideTTL := config.ClientSideCacheTTL
+	if clientSideTTL == 0 {
+		clientSideTTL = defaultClientSideCacheTTL
+	}
+
 	c := &RueidisClient{
-		client: client,
-		config: config,
-		logger: logger,
+		client:        client,
+		config:        config,
+		clientSideTTL: clientSideTTL,
+		logger:        logger,
 	}
 	duration := promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
 		Name:    "thanos_redis_operation_duration_seconds",
@@ -87,6 +109,15 @@ func NewRueidisClientWithConfig(logger log.Logger, name string, config RedisClie
 	c.durationSet = duration.WithLabelValues(opSet)
 	c.durationSetMulti = duration.WithLabelValues(opSetMulti)
 	c.durationGetMulti = duration.WithLabelValues(opGetMulti)
+
+	getMultiResults := promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_redis_get_multi_results_total",
+		Help: "Results of GetMulti/GetMultiAsync lookups, split by where the value was served from.",
+	}, []string{"result"})
+	c.getMultiLocalHit = getMultiResults.WithLabelValues("local_cache_hit")
+	c.getMultiRedisHit = getMultiResults.WithLabelValues("redis_hit")
+	c.getMultiMiss = getMultiResults.WithLabelValues("miss")
+
 	return c, nil
 }
 
@@ -121,6 +152,30 @@ func (c *RueidisClient) SetMulti(ctx context.Context, data map[string][]byte, tt
 	c.durationSetMulti.Observe(time.Since(start).Seconds())
 }
 
+// getMultiCmds builds one cacheable GET per key instead of a single MGET, so
+// rueidis's opt-in client-side cache tracks and expires each key
+// independently, honoring whatever TTL the server set on it.
+func (c *RueidisClient) getMultiCmds(keys []string) []rueidis.CacheableTTL {
+	cmds := make([]rueidis.CacheableTTL, len(keys))
+	for i, k := range keys {
+		cmds[i] = rueidis.CT(c.client.B().Get().Key(k).Cache(), c.clientSideTTL)
+	}
+	return cmds
+}
+
+// observeGetResult records whether resp was served from rueidis's local
+// cache, from redis, or missed entirely.
+func (c *RueidisClient) observeGetResult(resp rueidis.RedisResult) {
+	switch {
+	case resp.IsCacheHit():
+		c.getMultiLocalHit.Inc()
+	case resp.Error() == nil:
+		c.getMultiRedisHit.Inc()
+	default:
+		c.getMultiMiss.Inc()
+	}
+}
+
 // GetMulti implement RemoteCacheClient.
 func (c *RueidisClient) GetMulti(ctx context.Context, keys []string) map[string][]byte {
 	if len(keys) == 0 {
@@ -129,19 +184,54 @@ func (c *RueidisClient) GetMulti(ctx context.Context, keys []string) map[string]
 	start := time.Now()
 	results := make(map[string][]byte, len(keys))
 
-	resps, err := c.client.DoCache(ctx, c.client.B().Mget().Key(keys...).Cache(), 8*time.Hour).ToArray()
-	if err != nil {
-		level.Warn(c.logger).Log("msg", "failed to mget items from redis", "err", err, "items", len(resps))
-	}
+	resps := c.client.DoMultiCache(ctx, c.getMultiCmds(keys)...)
 	for i, resp := range resps {
+		c.observeGetResult(resp)
 		if val, err := resp.ToString(); err == nil {
 			results[keys[i]] = stringToBytes(val)
+		} else if !rueidis.IsRedisNil(err) {
+			level.Warn(c.logger).Log("msg", "failed to get item from redis", "err", err, "key", keys[i])
 		}
 	}
 	c.durationGetMulti.Observe(time.Since(start).Seconds())
 	return results
 }
 
+// GetMultiAsync is like GetMulti but returns a channel instead of a map, so
+// a caller such as RueidisCache.FetchAsync doesn't have to block its own
+// goroutine until the whole batch resolves. The keys are still fetched with
+// one pipelined DoMultiCache call underneath, so there's no earlier
+// per-key delivery than GetMulti gets - only the caller's blocking on it is
+// removed. The channel is closed once every key has been observed.
+func (c *RueidisClient) GetMultiAsync(ctx context.Context, keys []string) <-chan KeyValue {
+	out := make(chan KeyValue, len(keys))
+	if len(keys) == 0 {
+		close(out)
+		return out
+	}
+
+	go func() {
+		defer close(out)
+		start := time.Now()
+
+		resps := c.client.DoMultiCache(ctx, c.getMultiCmds(keys)...)
+		for i, resp := range resps {
+			c.observeGetResult(resp)
+			val, err := resp.ToString()
+			if err != nil {
+				if !rueidis.IsRedisNil(err) {
+					level.Warn(c.logger).Log("msg", "failed to get item from redis", "err", err, "key", keys[i])
+				}
+				continue
+			}
+			out <- KeyValue{Key: keys[i], Value: stringToBytes(val)}
+		}
+		c.durationGetMulti.Observe(time.Since(start).Seconds())
+	}()
+
+	return out
+}
+
 // Stop implement RemoteCacheClient.
 func (c *RueidisClient) Stop() {
 	c.client.Close()
@@ -2,6 +2,7 @@ package grpccache
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"os"
@@ -15,6 +16,7 @@ import (
 	"github.com/thanos-io/thanos/pkg/cache"
 	"github.com/thanos-io/thanos/pkg/model"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"github.com/thanos-io/thanos/pkg/tenancy"
 	"google.golang.org/grpc"
 )
 
@@ -91,15 +93,31 @@ type seriesInterceptor struct {
 	cachedCalls prometheus.Counter
 }
 
-func hashReqTarget(r *storepb.SeriesRequest, target string) string {
+// hashReqTarget hashes the request together with the target and tenant, so
+// two tenants issuing an identical SeriesRequest against the same target
+// never collide on the same cache entry. target and tenant are each
+// length-prefixed before being written, so a boundary-shifting pair like
+// (target="ab", tenant="c") and (target="a", tenant="bc") can't hash equal.
+func hashReqTarget(r *storepb.SeriesRequest, target, tenant string) string {
 	h := xxhash.New()
-	_, _ = h.WriteString(target)
+	writeLengthPrefixed(h, target)
+	writeLengthPrefixed(h, tenant)
 	m, _ := r.Marshal()
 	_, _ = h.Write(m)
 
 	return string(h.Sum(nil))
 }
 
+// writeLengthPrefixed writes s to w prefixed with its length as a fixed-size
+// big-endian uint64, so s can never be confused with a different-length
+// neighbor when several strings are written back-to-back into the same hash.
+func writeLengthPrefixed(w io.Writer, s string) {
+	var scratch [8]byte
+	binary.BigEndian.PutUint64(scratch[:], uint64(len(s)))
+	_, _ = w.Write(scratch[:])
+	_, _ = w.Write([]byte(s))
+}
+
 func (i *seriesInterceptor) RecvMsg(m interface{}) error {
 	if i.hashedReq == "" {
 		return i.ClientStream.RecvMsg(m)
@@ -131,7 +149,8 @@ func (i *seriesInterceptor) RecvMsg(m interface{}) error {
 
 func (i *seriesInterceptor) SendMsg(m interface{}) error {
 	if req, ok := m.(*storepb.SeriesRequest); ok {
-		i.hashedReq = hashReqTarget(req, i.target)
+		tenant := tenancy.FromGRPCContext(i.ClientStream.Context())
+		i.hashedReq = hashReqTarget(req, i.target, tenant)
 
 		responses, err := getResponses(i.c, i.hashedReq)
 		if err == nil {
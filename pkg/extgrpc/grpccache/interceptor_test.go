@@ -0,0 +1,23 @@
+package grpccache
+
+import (
+	"testing"
+
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+)
+
+func TestHashReqTarget_DistinctTenants(t *testing.T) {
+	req := &storepb.SeriesRequest{}
+
+	a := hashReqTarget(req, "ab", "c")
+	b := hashReqTarget(req, "a", "bc")
+	if a == b {
+		t.Fatalf("hashReqTarget collided across a target/tenant boundary shift: %q == %q", a, b)
+	}
+
+	a = hashReqTarget(req, "target", "tenant-1")
+	b = hashReqTarget(req, "target", "tenant-2")
+	if a == b {
+		t.Fatalf("hashReqTarget produced the same hash for two different tenants: %q == %q", a, b)
+	}
+}
@@ -8,12 +8,19 @@ import (
 	"net"
 
 	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/run"
 	"storj.io/drpc/drpcmux"
 	"storj.io/drpc/drpcserver"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// DRPCServer wraps a drpcserver.Server and the drpcmux.Mux it serves, giving
+// callers a place to register services (see RegisterStoreAPI) before
+// Serve is started. It's the DRPC counterpart of the gRPC server started
+// alongside it; see RunAlongsideGRPC to add it to a command's run.Group
+// behind a --drpc-address flag.
 type DRPCServer struct {
 	s *drpcserver.Server
 	m *drpcmux.Mux
@@ -32,3 +39,29 @@ func NewServer(logger log.Logger, reg prometheus.Registerer) *DRPCServer {
 func (d *DRPCServer) GetMux() *drpcmux.Mux {
 	return d.m
 }
+
+// RunAlongsideGRPC adds srv to g so it listens on addr for the lifetime of
+// the group, the same way the gRPC server is added to a command's run.Group.
+// It is a no-op if addr is empty, so commands can wire it up unconditionally
+// behind a --drpc-address flag that defaults to disabled.
+func RunAlongsideGRPC(g *run.Group, logger log.Logger, srv *DRPCServer, addr string) error {
+	if addr == "" {
+		return nil
+	}
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	g.Add(func() error {
+		level.Info(logger).Log("msg", "listening for DRPC requests", "address", addr)
+		return srv.Serve(ctx, lis)
+	}, func(error) {
+		cancel()
+		_ = lis.Close()
+	})
+
+	return nil
+}
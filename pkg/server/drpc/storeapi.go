@@ -0,0 +1,100 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package drpc
+
+import (
+	"context"
+
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"storj.io/drpc"
+	"storj.io/drpc/drpcmux"
+)
+
+// storeAPIEncoding (de)serializes storepb messages using their hand-written
+// Marshal/Unmarshal methods, the same ones the gRPC transport already relies
+// on, instead of protoc-gen-go-drpc generated glue.
+type storeAPIEncoding struct{}
+
+func (storeAPIEncoding) Marshal(msg drpc.Message) ([]byte, error) {
+	return msg.(interface{ Marshal() ([]byte, error) }).Marshal()
+}
+
+func (storeAPIEncoding) Unmarshal(data []byte, msg drpc.Message) error {
+	return msg.(interface{ Unmarshal([]byte) error }).Unmarshal(data)
+}
+
+// DRPCStoreAPIServer is the DRPC counterpart of storepb.StoreServer: Info,
+// LabelNames and LabelValues are unary, Series is server-streaming.
+type DRPCStoreAPIServer interface {
+	Info(context.Context, *storepb.InfoRequest) (*storepb.InfoResponse, error)
+	Series(*storepb.SeriesRequest, DRPCStoreAPI_SeriesStream) error
+	LabelNames(context.Context, *storepb.LabelNamesRequest) (*storepb.LabelNamesResponse, error)
+	LabelValues(context.Context, *storepb.LabelValuesRequest) (*storepb.LabelValuesResponse, error)
+}
+
+// DRPCStoreAPI_SeriesStream is the server side of the Series stream: send
+// zero or more responses, then return to close it.
+type DRPCStoreAPI_SeriesStream interface {
+	drpc.Stream
+	Send(*storepb.SeriesResponse) error
+}
+
+// SeriesServer adapts a raw drpc.Stream into a DRPCStoreAPI_SeriesStream,
+// the DRPC equivalent of the storepb.Store_SeriesServer the gRPC transport
+// hands to BucketStore.Series.
+type SeriesServer struct {
+	drpc.Stream
+}
+
+func (s *SeriesServer) Send(resp *storepb.SeriesResponse) error {
+	return s.MsgSend(resp, storeAPIEncoding{})
+}
+
+func (s *SeriesServer) Recv() (*storepb.SeriesRequest, error) {
+	req := new(storepb.SeriesRequest)
+	if err := s.MsgRecv(req, storeAPIEncoding{}); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// storeAPIDescription describes the thanos.Store service to drpcmux.Mux, the
+// way protoc-gen-go-drpc would generate it from storepb's proto definitions.
+type storeAPIDescription struct{}
+
+func (storeAPIDescription) NumMethods() int { return 4 }
+
+func (storeAPIDescription) Method(n int) (string, drpc.Encoding, drpc.Receiver, interface{}, bool) {
+	switch n {
+	case 0:
+		return "/thanos.Store/Info", storeAPIEncoding{},
+			func(srv interface{}, ctx context.Context, in1, in2 interface{}) (drpc.Message, error) {
+				return srv.(DRPCStoreAPIServer).Info(ctx, in1.(*storepb.InfoRequest))
+			}, DRPCStoreAPIServer.Info, true
+	case 1:
+		return "/thanos.Store/Series", storeAPIEncoding{},
+			func(srv interface{}, ctx context.Context, in1, in2 interface{}) (drpc.Message, error) {
+				return nil, srv.(DRPCStoreAPIServer).Series(in1.(*storepb.SeriesRequest), &SeriesServer{Stream: in2.(drpc.Stream)})
+			}, DRPCStoreAPIServer.Series, true
+	case 2:
+		return "/thanos.Store/LabelNames", storeAPIEncoding{},
+			func(srv interface{}, ctx context.Context, in1, in2 interface{}) (drpc.Message, error) {
+				return srv.(DRPCStoreAPIServer).LabelNames(ctx, in1.(*storepb.LabelNamesRequest))
+			}, DRPCStoreAPIServer.LabelNames, true
+	case 3:
+		return "/thanos.Store/LabelValues", storeAPIEncoding{},
+			func(srv interface{}, ctx context.Context, in1, in2 interface{}) (drpc.Message, error) {
+				return srv.(DRPCStoreAPIServer).LabelValues(ctx, in1.(*storepb.LabelValuesRequest))
+			}, DRPCStoreAPIServer.LabelValues, true
+	default:
+		return "", nil, nil, nil, false
+	}
+}
+
+// RegisterStoreAPI registers srv on mux under the same "/thanos.Store/..."
+// rpc names the gRPC transport uses, so store-gateway and querier can be
+// pointed at either transport interchangeably.
+func RegisterStoreAPI(mux *drpcmux.Mux, srv DRPCStoreAPIServer) error {
+	return mux.Register(srv, storeAPIDescription{})
+}
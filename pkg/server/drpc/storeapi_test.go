@@ -0,0 +1,32 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package drpc
+
+import (
+	"testing"
+
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+)
+
+// BenchmarkStoreAPIEncoding_MarshalUnmarshal measures the cost of
+// storeAPIEncoding's hand-written Marshal/Unmarshal, the same path every
+// unary RPC and every Series response registered via RegisterStoreAPI goes
+// through.
+func BenchmarkStoreAPIEncoding_MarshalUnmarshal(b *testing.B) {
+	req := &storepb.InfoRequest{}
+	enc := storeAPIEncoding{}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data, err := enc.Marshal(req)
+		if err != nil {
+			b.Fatalf("Marshal: %v", err)
+		}
+		out := new(storepb.InfoRequest)
+		if err := enc.Unmarshal(data, out); err != nil {
+			b.Fatalf("Unmarshal: %v", err)
+		}
+	}
+}
@@ -7,15 +7,67 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
 
+	"github.com/cespare/xxhash/v2"
 	"github.com/dgraph-io/badger/v3"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/oklog/ulid"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/storage"
+
+	"github.com/thanos-io/thanos/pkg/tenancy"
 )
 
+// tenantPrefixed joins tenant and s with a length prefix instead of a plain
+// separator, so a tenant (attacker-controlled via the THANOS-TENANT header)
+// that happens to contain the separator can't shift the boundary and produce
+// the same string as a different, unintended tenant/s pair.
+func tenantPrefixed(tenant, s string) string {
+	return strconv.Itoa(len(tenant)) + ":" + tenant + ":" + s
+}
+
+// expandedPostingsCodec identifies how the value passed to
+// StoreExpandedPostings was encoded, so that entries produced by different
+// codecs never collide in the same cache namespace.
+type expandedPostingsCodec byte
+
+const (
+	expandedPostingsCodecRaw expandedPostingsCodec = iota
+	expandedPostingsCodecSnappy
+)
+
+// expandedPostingsCacheKey derives a stable cache key for the fully-resolved
+// postings set matching matchers. The matcher set is sorted before hashing so
+// that logically identical queries share a key regardless of matcher order.
+// The key is prefixed with tenant so postings resolved for one tenant are
+// never handed back to another.
+func expandedPostingsCacheKey(tenant string, blockID ulid.ULID, matchers []*labels.Matcher, codec expandedPostingsCodec) string {
+	sorted := make([]*labels.Matcher, len(matchers))
+	copy(sorted, matchers)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Name != sorted[j].Name {
+			return sorted[i].Name < sorted[j].Name
+		}
+		if sorted[i].Value != sorted[j].Value {
+			return sorted[i].Value < sorted[j].Value
+		}
+		return sorted[i].Type < sorted[j].Type
+	})
+
+	h := xxhash.New()
+	for _, m := range sorted {
+		_, _ = h.WriteString(m.Name)
+		_, _ = h.WriteString("=")
+		_, _ = h.WriteString(m.Value)
+		_, _ = h.Write([]byte{byte(m.Type)})
+	}
+
+	return tenantPrefixed(tenant, fmt.Sprintf("EP:%s:%d:%x", blockID.String(), codec, h.Sum64()))
+}
+
 // BadgerIndexCache is a memcached-based index cache.
 type BadgerIndexCache struct {
 	db *badger.DB
@@ -48,20 +100,27 @@ func NewBadgerIndexCache(logger log.Logger, ic IndexCache) (*BadgerIndexCache, e
 	return c, nil
 }
 
+// tenantScopedLabel namespaces l under the tenant found in ctx so that the
+// fallback IndexCache, which has no notion of tenancy, never hands postings
+// cached for one tenant back to another.
+func tenantScopedLabel(ctx context.Context, l labels.Label) labels.Label {
+	return labels.Label{Name: l.Name, Value: tenantPrefixed(tenancy.FromGRPCContext(ctx), l.Value)}
+}
+
 // StorePostings sets the postings identified by the ulid and label to the value v.
 // The function enqueues the request and returns immediately: the entry will be
 // asynchronously stored in the cache.
 func (c *BadgerIndexCache) StorePostings(ctx context.Context, blockID ulid.ULID, l labels.Label, v []byte) {
 	fmt.Println("storing badger postings")
 	if l.Name == "app" && l.Value == "core" {
-		key := cacheKey{blockID, cacheKeyPostings(l)}.string()
+		key := tenantPrefixed(tenancy.FromGRPCContext(ctx), cacheKey{blockID, cacheKeyPostings(l)}.string())
 
 		wb := c.db.NewWriteBatch()
 		wb.Set([]byte(key), v)
 		wb.Flush()
 		fmt.Println("badger stored postings")
 	} else {
-		c.ic.StorePostings(ctx, blockID, l, v)
+		c.ic.StorePostings(ctx, blockID, tenantScopedLabel(ctx, l), v)
 	}
 }
 
@@ -87,8 +146,8 @@ func (c *BadgerIndexCache) FetchMultiPostings(ctx context.Context, blockID ulid.
 	}
 
 	if foundCore {
+		key := tenantPrefixed(tenancy.FromGRPCContext(ctx), cacheKey{blockID, cacheKeyPostings(coreLabel)}.string())
 		c.db.View(func(txn *badger.Txn) error {
-			key := cacheKey{blockID, cacheKeyPostings(coreLabel)}.string()
 
 			item, err := txn.Get([]byte(key))
 			if err != nil {
@@ -106,12 +165,29 @@ func (c *BadgerIndexCache) FetchMultiPostings(ctx context.Context, blockID ulid.
 		})
 	}
 
-	hits, misses = c.ic.FetchMultiPostings(ctx, blockID, lbls)
+	// The fallback cache has no notion of tenancy, so scope every remaining
+	// label by tenant before delegating to it and unscope the results on
+	// the way back out.
+	scoped := make([]labels.Label, len(lbls))
+	unscope := make(map[labels.Label]labels.Label, len(lbls))
+	for i, l := range lbls {
+		sl := tenantScopedLabel(ctx, l)
+		scoped[i] = sl
+		unscope[sl] = l
+	}
 
-	misses = append(misses, extraMisses...)
-	if hits == nil {
-		hits = make(map[labels.Label][]byte)
+	scopedHits, scopedMisses := c.ic.FetchMultiPostings(ctx, blockID, scoped)
+
+	hits = make(map[labels.Label][]byte, len(scopedHits))
+	for k, v := range scopedHits {
+		hits[unscope[k]] = v
+	}
+	misses = make([]labels.Label, len(scopedMisses))
+	for i, m := range scopedMisses {
+		misses[i] = unscope[m]
 	}
+
+	misses = append(misses, extraMisses...)
 	for k, v := range extraHits {
 		hits[k] = v
 	}
@@ -119,6 +195,53 @@ func (c *BadgerIndexCache) FetchMultiPostings(ctx context.Context, blockID ulid.
 	return hits, misses
 }
 
+// StoreExpandedPostings sets the fully-resolved (intersected/merged) postings
+// for the given block and matcher set. Unlike StorePostings, which caches a
+// single label's posting list, this caches the result of expanding an entire
+// matcher combination so repeat queries skip the expansion step entirely.
+// The badger write is flushed before the value is also passed on to the
+// fallback IndexCache.
+func (c *BadgerIndexCache) StoreExpandedPostings(ctx context.Context, blockID ulid.ULID, matchers []*labels.Matcher, v []byte) {
+	key := expandedPostingsCacheKey(tenancy.FromGRPCContext(ctx), blockID, matchers, expandedPostingsCodecRaw)
+
+	wb := c.db.NewWriteBatch()
+	if err := wb.Set([]byte(key), v); err != nil {
+		level.Warn(c.logger).Log("msg", "failed to store expanded postings", "err", err, "block", blockID)
+	} else if err := wb.Flush(); err != nil {
+		level.Warn(c.logger).Log("msg", "failed to flush expanded postings", "err", err, "block", blockID)
+	}
+
+	// Badger is only a local staging cache in front of c.ic: write through
+	// so the entry is still available once it's evicted here, or to peers
+	// backed by a shared, non-Badger IndexCache.
+	c.ic.StoreExpandedPostings(ctx, blockID, matchers, v)
+}
+
+// FetchExpandedPostings fetches the fully-resolved postings previously stored
+// for blockID and matchers, falling back to c.ic if badger has no entry for
+// it. The returned bool reports whether the value was found.
+func (c *BadgerIndexCache) FetchExpandedPostings(ctx context.Context, blockID ulid.ULID, matchers []*labels.Matcher) ([]byte, bool) {
+	key := expandedPostingsCacheKey(tenancy.FromGRPCContext(ctx), blockID, matchers, expandedPostingsCodecRaw)
+
+	var val []byte
+	err := c.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err != nil {
+			return err
+		}
+		val, err = item.ValueCopy(nil)
+		return err
+	})
+	if err == nil {
+		return val, true
+	}
+	if !errors.Is(err, badger.ErrKeyNotFound) {
+		level.Warn(c.logger).Log("msg", "failed to fetch expanded postings", "err", err, "block", blockID)
+	}
+
+	return c.ic.FetchExpandedPostings(ctx, blockID, matchers)
+}
+
 // StoreSeries sets the series identified by the ulid and id to the value v.
 // The function enqueues the request and returns immediately: the entry will be
 // asynchronously stored in the cache.
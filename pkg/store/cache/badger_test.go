@@ -0,0 +1,34 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package storecache
+
+import "testing"
+
+func TestTenantPrefixed_DistinctTenants(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		tenantA, valueA string
+		tenantB, valueB string
+	}{
+		{
+			name:    "separator inside tenant shifts the naive boundary",
+			tenantA: "a|b", valueA: "c",
+			tenantB: "a", valueB: "b|c",
+		},
+		{
+			name:    "different tenants, same total length",
+			tenantA: "tenant-1", valueA: "x",
+			tenantB: "tenant-2", valueB: "x",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tenantPrefixed(tc.tenantA, tc.valueA)
+			want := tenantPrefixed(tc.tenantB, tc.valueB)
+			if got == want {
+				t.Fatalf("tenantPrefixed(%q, %q) == tenantPrefixed(%q, %q) = %q, want distinct cache entries",
+					tc.tenantA, tc.valueA, tc.tenantB, tc.valueB, got)
+			}
+		})
+	}
+}
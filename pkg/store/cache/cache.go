@@ -0,0 +1,41 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package storecache
+
+import (
+	"context"
+
+	"github.com/oklog/ulid"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// IndexCache is the cache used for the index data of a TSDB block: postings,
+// expanded postings and series. BadgerIndexCache wraps one of these to add a
+// local staging layer in front of it.
+type IndexCache interface {
+	// StorePostings sets the postings identified by the ulid and label to
+	// the value v.
+	StorePostings(ctx context.Context, blockID ulid.ULID, l labels.Label, v []byte)
+	// FetchMultiPostings fetches multiple postings - each identified by a
+	// label - and returns a map containing cache hits, along with a list of
+	// missing keys.
+	FetchMultiPostings(ctx context.Context, blockID ulid.ULID, lbls []labels.Label) (hits map[labels.Label][]byte, misses []labels.Label)
+
+	// StoreExpandedPostings sets the fully-resolved (intersected/merged)
+	// postings for the given block and matcher set.
+	StoreExpandedPostings(ctx context.Context, blockID ulid.ULID, matchers []*labels.Matcher, v []byte)
+	// FetchExpandedPostings fetches the fully-resolved postings previously
+	// stored by StoreExpandedPostings. The returned bool reports whether the
+	// value was found.
+	FetchExpandedPostings(ctx context.Context, blockID ulid.ULID, matchers []*labels.Matcher) ([]byte, bool)
+
+	// StoreSeries sets the series identified by the ulid and id to the
+	// value v.
+	StoreSeries(ctx context.Context, blockID ulid.ULID, id storage.SeriesRef, v []byte)
+	// FetchMultiSeries fetches multiple series - each identified by ID -
+	// from the cache and returns a map containing cache hits, along with a
+	// list of missing IDs.
+	FetchMultiSeries(ctx context.Context, blockID ulid.ULID, ids []storage.SeriesRef) (hits map[storage.SeriesRef][]byte, misses []storage.SeriesRef)
+}
@@ -0,0 +1,97 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package store
+
+import (
+	"context"
+	"net"
+
+	"storj.io/drpc"
+	"storj.io/drpc/drpcconn"
+
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+)
+
+// drpcStoreAPIEncoding mirrors pkg/server/drpc's storeAPIEncoding: it
+// (de)serializes storepb messages using their own Marshal/Unmarshal methods.
+type drpcStoreAPIEncoding struct{}
+
+func (drpcStoreAPIEncoding) Marshal(msg drpc.Message) ([]byte, error) {
+	return msg.(interface{ Marshal() ([]byte, error) }).Marshal()
+}
+
+func (drpcStoreAPIEncoding) Unmarshal(data []byte, msg drpc.Message) error {
+	return msg.(interface{ Unmarshal([]byte) error }).Unmarshal(data)
+}
+
+// DRPCStoreClient talks to a StoreAPI over DRPC. It's an alternative to the
+// generated gRPC storepb.StoreClient for peers started with --drpc-address,
+// where DRPC's lighter framing pays off on high-fanout Series streams.
+type DRPCStoreClient struct {
+	conn *drpcconn.Conn
+}
+
+// NewDRPCStoreClient wraps an already-dialed connection into a client. The
+// caller owns dialing (e.g. net.Dial("tcp", peerDRPCAddress)) and is
+// responsible for calling Close.
+func NewDRPCStoreClient(rawConn net.Conn) *DRPCStoreClient {
+	return &DRPCStoreClient{conn: drpcconn.New(rawConn)}
+}
+
+func (c *DRPCStoreClient) Close() error {
+	return c.conn.Close()
+}
+
+func (c *DRPCStoreClient) Info(ctx context.Context, in *storepb.InfoRequest) (*storepb.InfoResponse, error) {
+	out := new(storepb.InfoResponse)
+	if err := c.conn.Invoke(ctx, "/thanos.Store/Info", drpcStoreAPIEncoding{}, in, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *DRPCStoreClient) LabelNames(ctx context.Context, in *storepb.LabelNamesRequest) (*storepb.LabelNamesResponse, error) {
+	out := new(storepb.LabelNamesResponse)
+	if err := c.conn.Invoke(ctx, "/thanos.Store/LabelNames", drpcStoreAPIEncoding{}, in, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *DRPCStoreClient) LabelValues(ctx context.Context, in *storepb.LabelValuesRequest) (*storepb.LabelValuesResponse, error) {
+	out := new(storepb.LabelValuesResponse)
+	if err := c.conn.Invoke(ctx, "/thanos.Store/LabelValues", drpcStoreAPIEncoding{}, in, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Series opens a Series stream and sends the request, returning a client to
+// read the response stream from.
+func (c *DRPCStoreClient) Series(ctx context.Context, in *storepb.SeriesRequest) (*DRPCSeriesClient, error) {
+	stream, err := c.conn.NewStream(ctx, "/thanos.Store/Series", drpcStoreAPIEncoding{})
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.MsgSend(in, drpcStoreAPIEncoding{}); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return &DRPCSeriesClient{Stream: stream}, nil
+}
+
+// DRPCSeriesClient is the client side of a Series stream.
+type DRPCSeriesClient struct {
+	drpc.Stream
+}
+
+func (c *DRPCSeriesClient) Recv() (*storepb.SeriesResponse, error) {
+	resp := new(storepb.SeriesResponse)
+	if err := c.MsgRecv(resp, drpcStoreAPIEncoding{}); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
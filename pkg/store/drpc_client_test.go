@@ -0,0 +1,32 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package store
+
+import (
+	"testing"
+
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+)
+
+// BenchmarkDRPCStoreAPIEncoding_MarshalUnmarshal measures the cost of the
+// hand-written Marshal/Unmarshal drpcStoreAPIEncoding uses instead of
+// protoc-gen-go-drpc generated glue, since that choice is on the hot path of
+// every DRPC call DRPCStoreClient makes.
+func BenchmarkDRPCStoreAPIEncoding_MarshalUnmarshal(b *testing.B) {
+	req := &storepb.SeriesRequest{MinTime: 0, MaxTime: 1}
+	enc := drpcStoreAPIEncoding{}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data, err := enc.Marshal(req)
+		if err != nil {
+			b.Fatalf("Marshal: %v", err)
+		}
+		out := new(storepb.SeriesRequest)
+		if err := enc.Unmarshal(data, out); err != nil {
+			b.Fatalf("Unmarshal: %v", err)
+		}
+	}
+}
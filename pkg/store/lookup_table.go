@@ -4,10 +4,14 @@
 package store
 
 import (
+	"encoding/binary"
 	"math"
 	"strings"
 
+	lru "github.com/hashicorp/golang-lru/v2"
 	"github.com/pkg/errors"
+
+	"github.com/cespare/xxhash/v2"
 )
 
 type adjusterFn func(uint64) uint64
@@ -66,3 +70,211 @@ func (b *LookupTableBuilder) PutString(s string) (uint64, error) {
 func (b *LookupTableBuilder) GetTable() map[uint64]string {
 	return b.table
 }
+
+// MarshalBinary encodes the table built so far as a sequence of
+// length-prefixed strings ordered by reference, so it can be sent once and
+// reused across many Series responses instead of resent with every one.
+func (b *LookupTableBuilder) MarshalBinary() ([]byte, error) {
+	// Each entry is at least 1 byte (its varint length), so this is a
+	// reasonable starting capacity.
+	buf := make([]byte, 0, binary.MaxVarintLen64+b.current)
+	var scratch [binary.MaxVarintLen64]byte
+
+	n := binary.PutUvarint(scratch[:], b.current)
+	buf = append(buf, scratch[:n]...)
+
+	for ref := uint64(0); ref < b.current; ref++ {
+		s := b.table[ref]
+		n := binary.PutUvarint(scratch[:], uint64(len(s)))
+		buf = append(buf, scratch[:n]...)
+		buf = append(buf, s...)
+	}
+
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a table previously produced by MarshalBinary,
+// replacing the builder's current contents.
+func (b *LookupTableBuilder) UnmarshalBinary(data []byte) error {
+	count, n := binary.Uvarint(data)
+	if n <= 0 {
+		return errors.New("lookup table: invalid header")
+	}
+	data = data[n:]
+
+	table := make(map[uint64]string, count)
+	reverseTable := make(map[string]uint64, count)
+
+	for ref := uint64(0); ref < count; ref++ {
+		l, n := binary.Uvarint(data)
+		if n <= 0 || uint64(n)+l > uint64(len(data)) {
+			return errors.Errorf("lookup table: truncated entry %d", ref)
+		}
+		data = data[n:]
+
+		s := string(data[:l])
+		data = data[l:]
+
+		table[ref] = s
+		reverseTable[s] = ref
+	}
+
+	b.table = table
+	b.reverseTable = reverseTable
+	b.current = count
+	return nil
+}
+
+// ContentHash returns a stable hash of the table's contents, suitable for
+// use as a DictionaryCache key so peers can negotiate "dictionary already
+// known, id=X" instead of resending it.
+func (b *LookupTableBuilder) ContentHash() (uint64, error) {
+	data, err := b.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	return xxhash.Sum64(data), nil
+}
+
+// FrozenLookupTable is a read-only, array-backed view of a LookupTableBuilder
+// that decodes reference-to-string in O(1) on the hot path, once no more
+// strings will be added.
+type FrozenLookupTable struct {
+	entries []string
+}
+
+// Lookup returns the string for ref, and whether it was present.
+func (f *FrozenLookupTable) Lookup(ref uint64) (string, bool) {
+	if ref >= uint64(len(f.entries)) {
+		return "", false
+	}
+	return f.entries[ref], true
+}
+
+// Freeze returns a compact array-backed lookup table of the strings added so
+// far. It does not stop further PutString calls on the builder; strings
+// added afterwards are not in the returned table.
+func (b *LookupTableBuilder) Freeze() *FrozenLookupTable {
+	entries := make([]string, b.current)
+	for ref, s := range b.table {
+		entries[ref] = s
+	}
+	return &FrozenLookupTable{entries: entries}
+}
+
+// DictionaryCache is a shared, size-bounded LRU of recently seen frozen
+// lookup tables, keyed by LookupTableBuilder.ContentHash. It lets a store
+// gateway and querier negotiate "dictionary already known, id=X" via a small
+// header instead of resending the whole dictionary with every Series
+// response that hits the same label universe.
+type DictionaryCache struct {
+	lru *lru.Cache[uint64, *FrozenLookupTable]
+}
+
+// NewDictionaryCache creates a DictionaryCache holding up to size entries.
+func NewDictionaryCache(size int) (*DictionaryCache, error) {
+	c, err := lru.New[uint64, *FrozenLookupTable](size)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating dictionary cache")
+	}
+	return &DictionaryCache{lru: c}, nil
+}
+
+// Get returns the frozen table for id, if known.
+func (d *DictionaryCache) Get(id uint64) (*FrozenLookupTable, bool) {
+	return d.lru.Get(id)
+}
+
+// Put registers t under id, evicting the least recently used entry if the
+// cache is full.
+func (d *DictionaryCache) Put(id uint64, t *FrozenLookupTable) {
+	d.lru.Add(id, t)
+}
+
+// ReferenceResolver resolves a reference adjusted by an adjusterFn (as
+// produced by NewReferenceAdjusterFactory) back to its original string,
+// using the FrozenLookupTable owned by the store that produced it. This
+// keeps string identity intact across a multi-store merge while each
+// store's dictionary is transferred and cached independently.
+type ReferenceResolver struct {
+	eachStore uint64
+	tables    []*FrozenLookupTable
+}
+
+// NewReferenceResolver builds a resolver for storeCount stores, using the
+// same partitioning NewReferenceAdjusterFactory assigned each store's
+// references from. tables must be indexed by store index, with a nil entry
+// for stores whose dictionary hasn't been resolved yet.
+func NewReferenceResolver(storeCount uint64, tables []*FrozenLookupTable) *ReferenceResolver {
+	return &ReferenceResolver{eachStore: maxStringsPerStore(storeCount), tables: tables}
+}
+
+// Resolve returns the string an adjusted reference originally pointed to.
+func (r *ReferenceResolver) Resolve(ref uint64) (string, bool) {
+	storeIndex := ref / r.eachStore
+	if storeIndex >= uint64(len(r.tables)) || r.tables[storeIndex] == nil {
+		return "", false
+	}
+	return r.tables[storeIndex].Lookup(ref % r.eachStore)
+}
+
+// MergeDictionaryResolver is the entry point a multi-store merge uses to
+// turn adjusted references (as produced by NewReferenceAdjusterFactory) back
+// into strings: it wires each participating store's dictionary, negotiated
+// through cache via "dictionary already known, id=X", into a single
+// ReferenceResolver spanning the whole merge.
+//
+// Nothing in this tree calls it yet: the multi-store merge path it's meant
+// to plug into isn't part of this checkout. Wire it in once that merge code
+// lands here.
+type MergeDictionaryResolver struct {
+	cache    *DictionaryCache
+	resolver *ReferenceResolver
+}
+
+// NewMergeDictionaryResolver builds a resolver for a merge across storeCount
+// stores backed by cache. RegisterDictionary must be called once per store,
+// even one resolved entirely from cache, before Resolve is used for it.
+func NewMergeDictionaryResolver(storeCount uint64, cache *DictionaryCache) *MergeDictionaryResolver {
+	return &MergeDictionaryResolver{
+		cache:    cache,
+		resolver: NewReferenceResolver(storeCount, make([]*FrozenLookupTable, storeCount)),
+	}
+}
+
+// RegisterDictionary makes storeIndex's dictionary available to Resolve. If
+// known is true, id is looked up in the shared DictionaryCache instead of
+// decoding raw, completing the "dictionary already known, id=X" negotiation.
+// Otherwise raw is decoded, frozen, and stored under its own ContentHash so
+// a later merge negotiating the same id can skip decoding it again.
+func (m *MergeDictionaryResolver) RegisterDictionary(storeIndex uint64, known bool, id uint64, raw []byte) error {
+	if known {
+		t, ok := m.cache.Get(id)
+		if !ok {
+			return errors.Errorf("merge dictionary: store %d: dictionary %d not in cache", storeIndex, id)
+		}
+		m.resolver.tables[storeIndex] = t
+		return nil
+	}
+
+	b := NewLookupTableBuilder(math.MaxUint64)
+	if err := b.UnmarshalBinary(raw); err != nil {
+		return errors.Wrapf(err, "merge dictionary: store %d", storeIndex)
+	}
+
+	contentID, err := b.ContentHash()
+	if err != nil {
+		return errors.Wrapf(err, "merge dictionary: store %d", storeIndex)
+	}
+
+	t := b.Freeze()
+	m.cache.Put(contentID, t)
+	m.resolver.tables[storeIndex] = t
+	return nil
+}
+
+// Resolve returns the string an adjusted reference originally pointed to,
+// among the dictionaries registered so far.
+func (m *MergeDictionaryResolver) Resolve(ref uint64) (string, bool) {
+	return m.resolver.Resolve(ref)
+}
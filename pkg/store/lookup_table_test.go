@@ -0,0 +1,162 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package store
+
+import (
+	"testing"
+)
+
+func TestLookupTableBuilder_MarshalUnmarshalBinary(t *testing.T) {
+	b := NewLookupTableBuilder(100)
+	for _, s := range []string{"foo", "bar", "baz", "foo"} {
+		if _, err := b.PutString(s); err != nil {
+			t.Fatalf("PutString(%q): %v", s, err)
+		}
+	}
+
+	data, err := b.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	got := NewLookupTableBuilder(100)
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+
+	if len(got.GetTable()) != len(b.GetTable()) {
+		t.Fatalf("expected %d entries, got %d", len(b.GetTable()), len(got.GetTable()))
+	}
+	for ref, s := range b.GetTable() {
+		if got.GetTable()[ref] != s {
+			t.Errorf("ref %d: expected %q, got %q", ref, s, got.GetTable()[ref])
+		}
+	}
+}
+
+func TestLookupTableBuilder_MaxElementsReached(t *testing.T) {
+	b := NewLookupTableBuilder(1)
+	if _, err := b.PutString("foo"); err != nil {
+		t.Fatalf("PutString: %v", err)
+	}
+	if _, err := b.PutString("bar"); err != MaxElementsReached {
+		t.Fatalf("expected MaxElementsReached, got %v", err)
+	}
+}
+
+func TestLookupTableBuilder_Freeze(t *testing.T) {
+	b := NewLookupTableBuilder(100)
+	fooRef, _ := b.PutString("foo")
+	barRef, _ := b.PutString("bar")
+
+	frozen := b.Freeze()
+
+	if s, ok := frozen.Lookup(fooRef); !ok || s != "foo" {
+		t.Errorf("Lookup(%d) = %q, %v; want \"foo\", true", fooRef, s, ok)
+	}
+	if s, ok := frozen.Lookup(barRef); !ok || s != "bar" {
+		t.Errorf("Lookup(%d) = %q, %v; want \"bar\", true", barRef, s, ok)
+	}
+	if _, ok := frozen.Lookup(barRef + 1); ok {
+		t.Errorf("Lookup(%d) = ok, want not found", barRef+1)
+	}
+}
+
+func TestLookupTableBuilder_ContentHash(t *testing.T) {
+	a := NewLookupTableBuilder(100)
+	_, _ = a.PutString("foo")
+	_, _ = a.PutString("bar")
+
+	b := NewLookupTableBuilder(100)
+	_, _ = b.PutString("foo")
+	_, _ = b.PutString("bar")
+
+	hashA, err := a.ContentHash()
+	if err != nil {
+		t.Fatalf("ContentHash: %v", err)
+	}
+	hashB, err := b.ContentHash()
+	if err != nil {
+		t.Fatalf("ContentHash: %v", err)
+	}
+	if hashA != hashB {
+		t.Errorf("expected identical tables to hash equally, got %d != %d", hashA, hashB)
+	}
+
+	_, _ = b.PutString("baz")
+	hashB2, err := b.ContentHash()
+	if err != nil {
+		t.Fatalf("ContentHash: %v", err)
+	}
+	if hashA == hashB2 {
+		t.Errorf("expected different tables to hash differently")
+	}
+}
+
+func TestDictionaryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c, err := NewDictionaryCache(1)
+	if err != nil {
+		t.Fatalf("NewDictionaryCache: %v", err)
+	}
+
+	first := (&LookupTableBuilder{}).Freeze()
+	second := (&LookupTableBuilder{}).Freeze()
+
+	c.Put(1, first)
+	c.Put(2, second)
+
+	if _, ok := c.Get(1); ok {
+		t.Errorf("expected id 1 to have been evicted")
+	}
+	if _, ok := c.Get(2); !ok {
+		t.Errorf("expected id 2 to still be present")
+	}
+}
+
+func TestMergeDictionaryResolver(t *testing.T) {
+	cache, err := NewDictionaryCache(10)
+	if err != nil {
+		t.Fatalf("NewDictionaryCache: %v", err)
+	}
+
+	adjusterFactory := NewReferenceAdjusterFactory(2)
+
+	store0 := NewLookupTableBuilder(100)
+	store0Adjust := adjusterFactory(0)
+	fooRef, _ := store0.PutString("foo")
+	fooRef = store0Adjust(fooRef)
+	store0Data, err := store0.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	store1 := NewLookupTableBuilder(100)
+	store1Adjust := adjusterFactory(1)
+	barRef, _ := store1.PutString("bar")
+	barRef = store1Adjust(barRef)
+	store1ContentID, err := store1.ContentHash()
+	if err != nil {
+		t.Fatalf("ContentHash: %v", err)
+	}
+	cache.Put(store1ContentID, store1.Freeze())
+
+	r := NewMergeDictionaryResolver(2, cache)
+	if err := r.RegisterDictionary(0, false, 0, store0Data); err != nil {
+		t.Fatalf("RegisterDictionary(store 0): %v", err)
+	}
+	if err := r.RegisterDictionary(1, true, store1ContentID, nil); err != nil {
+		t.Fatalf("RegisterDictionary(store 1): %v", err)
+	}
+
+	if s, ok := r.Resolve(fooRef); !ok || s != "foo" {
+		t.Errorf("Resolve(%d) = %q, %v; want \"foo\", true", fooRef, s, ok)
+	}
+	if s, ok := r.Resolve(barRef); !ok || s != "bar" {
+		t.Errorf("Resolve(%d) = %q, %v; want \"bar\", true", barRef, s, ok)
+	}
+
+	if err := r.RegisterDictionary(0, true, 999, nil); err == nil {
+		t.Fatalf("expected an error for an unknown cached dictionary id")
+	}
+}
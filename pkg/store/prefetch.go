@@ -3,77 +3,335 @@ package store
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/oklog/run"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/robfig/cron/v3"
+	"google.golang.org/grpc/metadata"
+	"gopkg.in/yaml.v2"
+
+	"github.com/thanos-io/thanos/pkg/tenancy"
 )
 
+// expandedPostingsCache is the subset of storecache.IndexCache the prefetcher
+// needs to avoid re-expanding the same matcher combination on every run.
+type expandedPostingsCache interface {
+	StoreExpandedPostings(ctx context.Context, blockID ulid.ULID, matchers []*labels.Matcher, v []byte)
+	FetchExpandedPostings(ctx context.Context, blockID ulid.ULID, matchers []*labels.Matcher) ([]byte, bool)
+}
+
+// PrefetchRule describes one set of matchers to warm ahead of time: the
+// window of block data to consider, how many blocks to fetch concurrently,
+// and how often to repeat.
+type PrefetchRule struct {
+	Name string `yaml:"name"`
+	// Matchers are joined with "," and parsed as a single vector selector,
+	// e.g. Matchers: []string{`app="core"`, `env="prod"`}. Only equality
+	// matchers are accepted.
+	Matchers []string `yaml:"matchers"`
+	// Lookback is how far back from now to look for matching blocks.
+	Lookback model.Duration `yaml:"lookback"`
+	// MinBlockTime excludes blocks newer than now-MinBlockTime, mirroring
+	// the previous hardcoded 2-day freshness filter.
+	MinBlockTime model.Duration `yaml:"min_block_time"`
+	// Concurrency bounds how many blocks are fetched at once for this rule.
+	Concurrency int `yaml:"concurrency"`
+	// Schedule is the interval on which the rule is re-run. Ignored if Cron
+	// is set.
+	Schedule model.Duration `yaml:"schedule"`
+	// Cron is a standard 5-field cron expression (e.g. "0 */2 * * *") the
+	// rule is re-run on. Takes precedence over Schedule when set, for
+	// operators who need time-of-day/day-of-week control a fixed interval
+	// can't express.
+	Cron string `yaml:"cron"`
+	// Tenants lists the tenants the rule warms: the matching blocks are
+	// fetched once per tenant and cached under that tenant's namespace, so
+	// warming one customer's dashboards never populates the shared cache
+	// namespace for another. Blocks themselves are not filtered by tenant.
+	// Empty means tenancy.DefaultTenant.
+	Tenants []string `yaml:"tenants"`
+
+	matchers []*labels.Matcher
+	cron     cron.Schedule
+}
+
+// effectiveTenants returns Tenants, or tenancy.DefaultTenant if none were
+// configured.
+func (r *PrefetchRule) effectiveTenants() []string {
+	if len(r.Tenants) == 0 {
+		return []string{tenancy.DefaultTenant}
+	}
+	return r.Tenants
+}
+
+func (r *PrefetchRule) init() error {
+	if r.Name == "" {
+		return errors.New("prefetch rule: name is required")
+	}
+	if len(r.Matchers) == 0 {
+		return errors.Errorf("prefetch rule %q: at least one matcher is required", r.Name)
+	}
+
+	matchers, err := parser.ParseMetricSelector("{" + strings.Join(r.Matchers, ",") + "}")
+	if err != nil {
+		return errors.Wrapf(err, "prefetch rule %q: parsing matchers", r.Name)
+	}
+	for _, m := range matchers {
+		// fetchBlock only resolves MatchEqual matchers before fetching
+		// postings, so caching under the full matcher set for any other
+		// type would make the expanded postings cache return series that
+		// were never actually filtered.
+		if m.Type != labels.MatchEqual {
+			return errors.Errorf("prefetch rule %q: matcher %q: only equality matchers are supported", r.Name, m)
+		}
+	}
+	r.matchers = matchers
+
+	if r.Concurrency <= 0 {
+		r.Concurrency = 1
+	}
+	if r.Cron != "" {
+		sched, err := cron.ParseStandard(r.Cron)
+		if err != nil {
+			return errors.Wrapf(err, "prefetch rule %q: parsing cron schedule %q", r.Name, r.Cron)
+		}
+		r.cron = sched
+	} else if r.Schedule <= 0 {
+		r.Schedule = model.Duration(time.Hour)
+	}
+	if r.Lookback <= 0 {
+		r.Lookback = model.Duration(14 * 24 * time.Hour)
+	}
+
+	return nil
+}
+
+// PrefetchConfig is the declarative policy for PostingsPrefetcher: a list of
+// rules, replacing the previously hardcoded {app="core"} matcher, 14-day
+// lookback and 2-day freshness filter.
+type PrefetchConfig struct {
+	Rules []PrefetchRule `yaml:"rules"`
+}
+
+// ParsePrefetchConfig parses and validates a YAML-encoded PrefetchConfig.
+func ParsePrefetchConfig(data []byte) (PrefetchConfig, error) {
+	var cfg PrefetchConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return PrefetchConfig{}, errors.Wrap(err, "parsing prefetch config")
+	}
+	for i := range cfg.Rules {
+		if err := cfg.Rules[i].init(); err != nil {
+			return PrefetchConfig{}, err
+		}
+	}
+	return cfg, nil
+}
+
+type prefetchMetrics struct {
+	runs     *prometheus.CounterVec
+	blocks   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+func newPrefetchMetrics(reg prometheus.Registerer) *prefetchMetrics {
+	return &prefetchMetrics{
+		runs: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_prefetch_runs_total",
+			Help: "Total number of postings prefetch runs, per rule.",
+		}, []string{"rule", "tenant"}),
+		blocks: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "thanos_prefetch_blocks_matched",
+			Help: "Total number of blocks matched by a postings prefetch rule.",
+		}, []string{"rule", "tenant"}),
+		duration: promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "thanos_prefetch_duration_seconds",
+			Help:    "Duration of a single postings prefetch run, per rule.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"rule", "tenant"}),
+	}
+}
+
+// PostingsPrefetcher periodically warms the postings needed for a set of
+// configured rules so that hot queries (e.g. dashboard reload storms) don't
+// pay the full matcher-expansion cost on the request path.
 type PostingsPrefetcher struct {
-	b *BucketStore
+	b     *BucketStore
+	cache expandedPostingsCache
+	cfg   PrefetchConfig
+
+	metrics *prefetchMetrics
+}
+
+// NewPostingsPrefetcher creates a PostingsPrefetcher driven by cfg. cache may
+// be nil, in which case blocks are always re-fetched.
+func NewPostingsPrefetcher(b *BucketStore, cache expandedPostingsCache, cfg PrefetchConfig, reg prometheus.Registerer) (*PostingsPrefetcher, error) {
+	return &PostingsPrefetcher{
+		b:       b,
+		cache:   cache,
+		cfg:     cfg,
+		metrics: newPrefetchMetrics(reg),
+	}, nil
+}
+
+// Run starts one scheduler goroutine per rule and blocks until ctx is
+// cancelled or a rule's scheduler returns an unrecoverable error.
+func (p *PostingsPrefetcher) Run(ctx context.Context) error {
+	g := &run.Group{}
+
+	for _, rule := range p.cfg.Rules {
+		rule := rule
+		runCtx, cancel := context.WithCancel(ctx)
+
+		g.Add(func() error {
+			return p.runRuleSchedule(runCtx, rule)
+		}, func(error) {
+			cancel()
+		})
+	}
+
+	return g.Run()
+}
+
+// runRuleSchedule runs rule each time its schedule fires, until ctx is
+// cancelled: on Cron's matches if the rule has one, otherwise every
+// Schedule interval.
+func (p *PostingsPrefetcher) runRuleSchedule(ctx context.Context, rule PrefetchRule) error {
+	if rule.cron != nil {
+		return p.runRuleCron(ctx, rule)
+	}
+
+	t := time.NewTicker(time.Duration(rule.Schedule))
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			if err := p.doFetchRule(rule); err != nil {
+				fmt.Println("prefetch rule failed", rule.Name, err)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 }
 
-func NewPostingsPrefetcher(b *BucketStore) (*PostingsPrefetcher, error) {
+// runRuleCron runs rule at every match of rule.cron, until ctx is cancelled.
+func (p *PostingsPrefetcher) runRuleCron(ctx context.Context, rule PrefetchRule) error {
+	for {
+		t := time.NewTimer(time.Until(rule.cron.Next(time.Now())))
 
-	return &PostingsPrefetcher{b: b}, nil
+		select {
+		case <-t.C:
+			if err := p.doFetchRule(rule); err != nil {
+				fmt.Println("prefetch rule failed", rule.Name, err)
+			}
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
 }
 
+// DoFetch runs every configured rule once, synchronously. It's mainly useful
+// for warming caches on startup, before the scheduler's first tick.
 func (p *PostingsPrefetcher) DoFetch() error {
+	for _, rule := range p.cfg.Rules {
+		if err := p.doFetchRule(rule); err != nil {
+			return errors.Wrapf(err, "prefetch rule %q", rule.Name)
+		}
+	}
+	return nil
+}
+
+func (p *PostingsPrefetcher) doFetchRule(rule PrefetchRule) error {
 	p.b.mtx.RLock()
 	defer p.b.mtx.RUnlock()
 
 	now := time.Now()
-	from := now.Add(-2 * 24 * time.Hour)
+	from := now.Add(-time.Duration(rule.Lookback))
+	upTo := now.Add(-time.Duration(rule.MinBlockTime))
 
-	var _ = from
-	fmt.Println("fetching!")
+	for _, tenant := range rule.effectiveTenants() {
+		tenant := tenant
 
-	g := &run.Group{}
+		sem := make(chan struct{}, rule.Concurrency)
+		g := &run.Group{}
 
-	for _, bs := range p.b.blockSets {
-		matchers := []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "app", "core")}
+		for _, bs := range p.b.blockSets {
+			blockMatchers, ok := bs.labelMatchers(rule.matchers...)
+			if !ok {
+				continue
+			}
 
-		blockMatchers, ok := bs.labelMatchers(matchers...)
-		if !ok {
-			continue
-		}
-		n := time.Now()
-		from := n.Add(-14 * 24 * time.Hour)
+			blocks := bs.getFor(from.UnixMilli(), upTo.UnixMilli(), 0, []*labels.Matcher{})
+			p.metrics.blocks.WithLabelValues(rule.Name, tenant).Add(float64(len(blocks)))
+			fmt.Println("rule", rule.Name, "tenant", tenant, "matched", len(blocks), "blocks", blockMatchers)
 
-		blocks := bs.getFor(from.UnixMilli(), n.UnixMilli(), 0, []*labels.Matcher{})
-		fmt.Println("matched", len(blocks), "blocks", blockMatchers)
+			for _, b := range blocks {
+				b := b
 
-		for _, b := range blocks {
+				g.Add(func() error {
+					sem <- struct{}{}
+					defer func() { <-sem }()
+					return p.fetchBlock(b, rule, tenant)
+				}, func(err error) {
+					if err != nil {
+						fmt.Println("error happened", err)
+					}
+				})
+			}
+		}
 
-			b := b
+		// Time the actual fetch work (g.Run), not the time spent merely
+		// enumerating and queueing blocks above.
+		start := time.Now()
+		err := g.Run()
+		p.metrics.runs.WithLabelValues(rule.Name, tenant).Inc()
+		p.metrics.duration.WithLabelValues(rule.Name, tenant).Observe(time.Since(start).Seconds())
+		if err != nil {
+			return err
+		}
+	}
 
-			g.Add(func() error {
-				ir := b.indexReader()
+	return nil
+}
 
-				defer ir.Close()
+func (p *PostingsPrefetcher) fetchBlock(b *bucketBlock, rule PrefetchRule, tenant string) error {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), tenancy.DefaultTenantHeader, tenant)
 
-				fmt.Println("fetching postings")
-				postings, err := ir.fetchPostings(context.Background(), []labels.Label{{Name: "app", Value: "core"}})
-				if err != nil {
-					return err
-				}
-				fmt.Println("fetchPostings finished")
+	ir := b.indexReader()
+	defer ir.Close()
 
-				var _ = postings
+	if p.cache != nil {
+		if _, ok := p.cache.FetchExpandedPostings(ctx, b.meta.ULID, rule.matchers); ok {
+			fmt.Println("expanded postings cache hit, skipping fetch")
+			return nil
+		}
+	}
 
-				return nil
-			}, func(err error) {
-				if err != nil {
-					fmt.Println("error happened", err)
-				}
-			})
+	lbls := make([]labels.Label, 0, len(rule.matchers))
+	for _, m := range rule.matchers {
+		if m.Type == labels.MatchEqual {
+			lbls = append(lbls, labels.Label{Name: m.Name, Value: m.Value})
 		}
 	}
 
-	err := g.Run()
+	postings, err := ir.fetchPostings(ctx, lbls)
 	if err != nil {
 		return err
 	}
 
+	if p.cache != nil {
+		p.cache.StoreExpandedPostings(ctx, b.meta.ULID, rule.matchers, postings)
+	}
+
 	return nil
 }
@@ -0,0 +1,88 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package store
+
+import (
+	"testing"
+)
+
+func TestParsePrefetchConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{
+			name: "equality matchers only, defaults applied",
+			yaml: `
+rules:
+  - name: core
+    matchers: ['app="core"']
+`,
+		},
+		{
+			name: "non-equality matcher is rejected",
+			yaml: `
+rules:
+  - name: core
+    matchers: ['app="core"', 'env!="dev"']
+`,
+			wantErr: true,
+		},
+		{
+			name: "missing name is rejected",
+			yaml: `
+rules:
+  - matchers: ['app="core"']
+`,
+			wantErr: true,
+		},
+		{
+			name: "missing matchers is rejected",
+			yaml: `
+rules:
+  - name: core
+`,
+			wantErr: true,
+		},
+		{
+			name: "valid cron schedule",
+			yaml: `
+rules:
+  - name: core
+    matchers: ['app="core"']
+    cron: "0 */2 * * *"
+`,
+		},
+		{
+			name: "invalid cron schedule is rejected",
+			yaml: `
+rules:
+  - name: core
+    matchers: ['app="core"']
+    cron: "not a cron expression"
+`,
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := ParsePrefetchConfig([]byte(tc.yaml))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(cfg.Rules) != 1 {
+				t.Fatalf("expected 1 rule, got %d", len(cfg.Rules))
+			}
+			if cfg.Rules[0].Concurrency != 1 {
+				t.Errorf("expected default concurrency 1, got %d", cfg.Rules[0].Concurrency)
+			}
+		})
+	}
+}
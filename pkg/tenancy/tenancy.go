@@ -0,0 +1,51 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package tenancy
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// DefaultTenantHeader is the gRPC metadata key requests carry their tenant
+// identifier in, unless a component is configured to use a different one.
+const DefaultTenantHeader = "THANOS-TENANT"
+
+// DefaultTenant is used whenever a request carries no tenant header at all,
+// so single-tenant deployments keep working under one well-known namespace
+// instead of an empty string leaking into cache keys.
+const DefaultTenant = "default-tenant"
+
+// FromGRPCContext extracts the tenant from ctx using DefaultTenantHeader. It
+// checks incoming metadata first (the common case on a server handling a
+// request), falling back to outgoing metadata so client-side code, such as
+// a stream interceptor, can read the tenant it is about to forward. It
+// returns DefaultTenant if the header is absent from both.
+func FromGRPCContext(ctx context.Context) string {
+	return FromGRPCContextWithHeader(ctx, DefaultTenantHeader)
+}
+
+// FromGRPCContextWithHeader is like FromGRPCContext but reads a
+// caller-configured header instead of DefaultTenantHeader.
+func FromGRPCContextWithHeader(ctx context.Context, header string) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if t := firstNonEmpty(md.Get(header)); t != "" {
+			return t
+		}
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if t := firstNonEmpty(md.Get(header)); t != "" {
+			return t
+		}
+	}
+	return DefaultTenant
+}
+
+func firstNonEmpty(vals []string) string {
+	if len(vals) == 0 {
+		return ""
+	}
+	return vals[0]
+}